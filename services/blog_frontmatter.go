@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatterDelimiter marks the start and end of the YAML block at the
+// top of a markdown blog post.
+var frontmatterDelimiter = []byte("---")
+
+// frontmatterDateLayout is the date format accepted in a post's
+// frontmatter, e.g. "2006-01-02".
+const frontmatterDateLayout = "2006-01-02"
+
+// blogPostFrontmatter is the YAML metadata block expected at the top of
+// a markdown blog post when BlogMarkdownDir is loaded without a
+// blog-config.json.
+type blogPostFrontmatter struct {
+	// Slug is the post's slug. Inferred from the filename when absent.
+	Slug string `yaml:"slug"`
+
+	// The Title of the blog post
+	Title string `yaml:"title"`
+
+	// The Summary of the blog post
+	Summary string `yaml:"summary"`
+
+	// Image is the image to use for the post
+	Image string `yaml:"image"`
+
+	// ImageAlt is the image alt to use for the post
+	ImageAlt string `yaml:"imageAlt"`
+
+	// Date is the publication date, formatted as frontmatterDateLayout
+	Date string `yaml:"date"`
+
+	// Tags the post is categorised under
+	Tags []string `yaml:"tags"`
+
+	// Draft posts are skipped when loading
+	Draft bool `yaml:"draft"`
+
+	// Pinned marks a post to be surfaced ahead of others
+	Pinned bool `yaml:"pinned"`
+}
+
+// loadBlogPostsFromFrontmatter scans blogMarkdownDirectory for *.md files
+// and builds a BlogPost from each one's YAML frontmatter and markdown
+// body, skipping any file marked draft: true.
+func loadBlogPostsFromFrontmatter(logger Logger, blogMarkdownDirectory string, renderer MarkdownRenderer) (map[string]*BlogPost, error) {
+	entries, err := ioutil.ReadDir(blogMarkdownDirectory)
+	if err != nil {
+		logger.Debug("Unable to list blog markdown directory", Fields{"path": blogMarkdownDirectory})
+		return nil, err
+	}
+
+	blogPosts := make(map[string]*BlogPost, 0)
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".md" {
+			continue
+		}
+
+		filePath := filepath.Join(blogMarkdownDirectory, entry.Name())
+		logger.Debug("Loading blog post", Fields{"path": filePath})
+
+		raw, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			logger.Debug("Unable to load blog post markdown from disk", Fields{"path": filePath})
+			return nil, err
+		}
+
+		blogPost, skip, err := parseFrontmatterBlogPost(entry.Name(), raw, renderer)
+		if err != nil {
+			logger.Debug("Blog post frontmatter found, but invalid", Fields{"path": filePath, "error": err.Error()})
+			return nil, err
+		}
+		if skip {
+			logger.Debug("Skipping draft blog post", Fields{"path": filePath})
+			continue
+		}
+
+		blogPosts[blogPost.Slug] = blogPost
+	}
+
+	return blogPosts, nil
+}
+
+// parseFrontmatterBlogPost splits raw into its frontmatter and markdown
+// body, renders the body to HTML, and returns the resulting BlogPost.
+// skip is true when the post is marked draft: true.
+func parseFrontmatterBlogPost(filename string, raw []byte, renderer MarkdownRenderer) (post *BlogPost, skip bool, err error) {
+	frontmatter, body, err := splitFrontmatter(raw)
+	if err != nil {
+		return nil, false, err
+	}
+
+	meta := &blogPostFrontmatter{}
+	if frontmatter != nil {
+		if err := yaml.Unmarshal(frontmatter, meta); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if meta.Draft {
+		return nil, true, nil
+	}
+
+	slug := meta.Slug
+	if slug == "" {
+		slug = strings.TrimSuffix(filename, filepath.Ext(filename))
+	}
+
+	var date time.Time
+	if meta.Date != "" {
+		date, err = time.Parse(frontmatterDateLayout, meta.Date)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid date %q: %w", meta.Date, err)
+		}
+	}
+
+	html, headings, err := renderMarkdown(renderer, body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &BlogPost{
+		Slug:        slug,
+		Title:       meta.Title,
+		Summary:     meta.Summary,
+		Image:       meta.Image,
+		ImageAlt:    meta.ImageAlt,
+		HTMLContent: html,
+		Headings:    headings,
+		Date:        date,
+		Tags:        meta.Tags,
+		Pinned:      meta.Pinned,
+	}, false, nil
+}
+
+// splitFrontmatter separates a leading "---"-delimited YAML block from
+// the rest of the document. frontmatter is nil when the document does
+// not start with a frontmatter block, in which case body is raw
+// unchanged.
+func splitFrontmatter(raw []byte) (frontmatter []byte, body []byte, err error) {
+	data := bytes.TrimLeft(raw, "\r\n")
+	if !bytes.HasPrefix(data, frontmatterDelimiter) {
+		return nil, raw, nil
+	}
+
+	rest := data[len(frontmatterDelimiter):]
+	if !startsWithNewlineOrEnd(rest) {
+		// The "---" isn't on its own line, e.g. a post whose body
+		// legitimately opens with a CommonMark thematic break
+		// ("----"). Treat it as ordinary content, not frontmatter.
+		return nil, raw, nil
+	}
+
+	end := indexOwnLineDelimiter(rest)
+	if end == -1 {
+		return nil, nil, fmt.Errorf("frontmatter block is not terminated with %q", frontmatterDelimiter)
+	}
+
+	frontmatter = bytes.TrimSpace(rest[:end])
+	body = bytes.TrimLeft(rest[end+len(frontmatterDelimiter):], "\r\n")
+	return frontmatter, body, nil
+}
+
+// indexOwnLineDelimiter finds the first occurrence of frontmatterDelimiter
+// in data that starts its own line (preceded by a line break, which data
+// always has at offset 0 since it immediately follows the opening
+// delimiter's own newline) and ends its own line (followed by a line
+// break or end of data). It returns -1 if no such occurrence exists, so
+// a frontmatter value containing the literal substring "---" mid-line
+// (e.g. `summary: "day --- night"`) is never mistaken for the
+// terminator.
+func indexOwnLineDelimiter(data []byte) int {
+	search := data
+	offset := 0
+	lineDelimiter := append([]byte{'\n'}, frontmatterDelimiter...)
+
+	for {
+		idx := bytes.Index(search, lineDelimiter)
+		if idx == -1 {
+			return -1
+		}
+
+		delimStart := offset + idx + 1 // +1 to skip past the leading '\n'
+		after := data[delimStart+len(frontmatterDelimiter):]
+		if startsWithNewlineOrEnd(after) {
+			return delimStart
+		}
+
+		search = search[idx+1:]
+		offset += idx + 1
+	}
+}
+
+// startsWithNewlineOrEnd reports whether b is empty or begins with a
+// line break, i.e. whether the delimiter immediately before b was on its
+// own line.
+func startsWithNewlineOrEnd(b []byte) bool {
+	if len(b) == 0 {
+		return true
+	}
+	if b[0] == '\n' {
+		return true
+	}
+	return b[0] == '\r' && len(b) > 1 && b[1] == '\n'
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// stubRenderer renders markdown as itself, so tests can assert on
+// HTMLContent without depending on a real MarkdownRenderer.
+type stubRenderer struct{}
+
+func (stubRenderer) Render(src []byte) (string, error) {
+	return string(src), nil
+}
+
+func TestSplitFrontmatter(t *testing.T) {
+	t.Run("well-formed block", func(t *testing.T) {
+		raw := []byte("---\ntitle: Hello\n---\n\nbody text\n")
+		frontmatter, body, err := splitFrontmatter(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(frontmatter) != "title: Hello" {
+			t.Errorf("frontmatter = %q, want %q", frontmatter, "title: Hello")
+		}
+		if string(body) != "body text\n" {
+			t.Errorf("body = %q, want %q", body, "body text\n")
+		}
+	})
+
+	t.Run("unterminated block is an error", func(t *testing.T) {
+		raw := []byte("---\ntitle: Hello\n\nbody text\n")
+		_, _, err := splitFrontmatter(raw)
+		if err == nil {
+			t.Fatal("expected an error for an unterminated frontmatter block")
+		}
+	})
+
+	t.Run("no frontmatter block", func(t *testing.T) {
+		raw := []byte("just a regular post\n")
+		frontmatter, body, err := splitFrontmatter(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if frontmatter != nil {
+			t.Errorf("frontmatter = %q, want nil", frontmatter)
+		}
+		if string(body) != string(raw) {
+			t.Errorf("body = %q, want unchanged raw %q", body, raw)
+		}
+	})
+
+	t.Run("leading thematic break is not mistaken for frontmatter", func(t *testing.T) {
+		raw := []byte("----\n\nSome post that opens with a horizontal rule.\n")
+		frontmatter, body, err := splitFrontmatter(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if frontmatter != nil {
+			t.Errorf("frontmatter = %q, want nil", frontmatter)
+		}
+		if string(body) != string(raw) {
+			t.Errorf("body = %q, want unchanged raw %q", body, raw)
+		}
+	})
+
+	t.Run("mid-line delimiter substring in a value is not mistaken for the terminator", func(t *testing.T) {
+		raw := []byte("---\nsummary: \"day --- night\"\n---\n\nbody text\n")
+		frontmatter, body, err := splitFrontmatter(raw)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wantFrontmatter := `summary: "day --- night"`
+		if string(frontmatter) != wantFrontmatter {
+			t.Errorf("frontmatter = %q, want %q", frontmatter, wantFrontmatter)
+		}
+		if string(body) != "body text\n" {
+			t.Errorf("body = %q, want %q", body, "body text\n")
+		}
+	})
+}
+
+func TestParseFrontmatterBlogPost(t *testing.T) {
+	t.Run("slug falls back to filename", func(t *testing.T) {
+		raw := []byte("---\ntitle: Hello\n---\n\nbody\n")
+		post, skip, err := parseFrontmatterBlogPost("my-post.md", raw, stubRenderer{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if skip {
+			t.Fatal("expected post not to be skipped")
+		}
+		if post.Slug != "my-post" {
+			t.Errorf("Slug = %q, want %q", post.Slug, "my-post")
+		}
+	})
+
+	t.Run("draft posts are skipped", func(t *testing.T) {
+		raw := []byte("---\ntitle: Hello\ndraft: true\n---\n\nbody\n")
+		_, skip, err := parseFrontmatterBlogPost("my-post.md", raw, stubRenderer{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !skip {
+			t.Fatal("expected draft post to be skipped")
+		}
+	})
+
+	t.Run("unterminated frontmatter is an error", func(t *testing.T) {
+		raw := []byte("---\ntitle: Hello\n\nbody\n")
+		_, _, err := parseFrontmatterBlogPost("my-post.md", raw, stubRenderer{})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(err.Error(), "not terminated") {
+			t.Errorf("error = %q, want it to mention the missing terminator", err)
+		}
+	})
+}
@@ -1,14 +1,25 @@
 package services
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"io/ioutil"
-	"gopkg.in/russross/blackfriday.v2"
 	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 const BlogConfigFilename = "blog-config.json"
 
+// reloadDebounce is how long the watcher waits for a burst of filesystem
+// events to go quiet before re-reading the blog config and markdown
+// files. A var, not a const, so tests can shorten it.
+var reloadDebounce = 3 * time.Second
+
 type BlogPost struct {
 	// Slug is the post's slug
 	Slug string
@@ -27,6 +38,22 @@ type BlogPost struct {
 
 	// HTMLContent is the blog posts content
 	HTMLContent string
+
+	// Headings is the post's table of contents, populated by renderers
+	// that implement HeadingRenderer (currently only GoldmarkRenderer).
+	Headings []Heading
+
+	// Date is the publication date of the post. Only populated when the
+	// post was loaded from markdown frontmatter.
+	Date time.Time
+
+	// Tags the post is categorised under. Only populated when the post
+	// was loaded from markdown frontmatter.
+	Tags []string
+
+	// Pinned marks a post to be surfaced ahead of others. Only populated
+	// when the post was loaded from markdown frontmatter.
+	Pinned bool
 }
 
 type BlogService interface {
@@ -43,7 +70,46 @@ type InMemoryBlogService struct {
 	Logger          Logger
 	BlogMarkdownDir string
 
-	BlogPosts map[string]*BlogPost
+	// RendererName selects the MarkdownRenderer used to render posts:
+	// RendererGoldmark (default) or RendererBlackfriday.
+	RendererName string
+	Renderer     MarkdownRenderer
+
+	// blogPostsMu guards BlogPosts so a reload triggered by the watcher
+	// can atomically swap the map without readers observing a torn map.
+	blogPostsMu sync.RWMutex
+	BlogPosts   map[string]*BlogPost
+
+	watcher *fsnotify.Watcher
+	stopCh  chan struct{}
+	doneCh  chan struct{}
+
+	// postHooks are invoked, once per post, whenever a reload surfaces a
+	// post that wasn't present in the previous snapshot.
+	postHooks []func(post *BlogPost)
+
+	// reloadHooks are invoked once per successful reload, regardless of
+	// whether any post is new (e.g. so FeedService can invalidate its
+	// cache when an existing post's content changes).
+	reloadHooks []func()
+}
+
+// OnNewPost registers a callback invoked after a successful reload, once
+// for each blog post that wasn't present in the previous snapshot (e.g.
+// so FederationService can deliver a Create activity to followers).
+// Hooks run synchronously after the swap on the goroutine that triggered
+// the reload (the watcher's, or Reload's caller); slow or unreliable work
+// should hand off to its own goroutine.
+func (bs *InMemoryBlogService) OnNewPost(hook func(post *BlogPost)) {
+	bs.postHooks = append(bs.postHooks, hook)
+}
+
+// OnReload registers a callback invoked once per successful reload.
+// Hooks run synchronously after the swap on the goroutine that triggered
+// the reload (the watcher's, or Reload's caller); slow or unreliable work
+// should hand off to its own goroutine.
+func (bs *InMemoryBlogService) OnReload(hook func()) {
+	bs.reloadHooks = append(bs.reloadHooks, hook)
 }
 
 type InMemoryBlogConfig struct {
@@ -77,8 +143,45 @@ type InMemoryBlogPostConfig struct {
 	Filename string `json:"filename"`
 }
 
-func NewInMemoryBlogService(logger Logger, blogMarkdownDirectory string) (*InMemoryBlogService, error) {
+func NewInMemoryBlogService(logger Logger, blogMarkdownDirectory string, rendererName string) (*InMemoryBlogService, error) {
+	renderer := newMarkdownRenderer(logger, rendererName)
+
+	blogPosts, err := loadBlogPosts(logger, blogMarkdownDirectory, renderer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InMemoryBlogService{
+		Logger:          logger,
+		BlogMarkdownDir: blogMarkdownDirectory,
+		RendererName:    rendererName,
+		Renderer:        renderer,
+		BlogPosts:       blogPosts,
+	}, nil
+}
+
+// loadBlogPosts loads every blog post from blogMarkdownDirectory. When a
+// blog-config.json is present it is used as the source of truth (legacy
+// mode); otherwise every *.md file in the directory is parsed directly,
+// reading its metadata from a YAML frontmatter block.
+func loadBlogPosts(logger Logger, blogMarkdownDirectory string, renderer MarkdownRenderer) (map[string]*BlogPost, error) {
 	blogConfigPath := filepath.Join(blogMarkdownDirectory, BlogConfigFilename)
+
+	if _, err := os.Stat(blogConfigPath); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		logger.Debug("No blog config file found, loading posts from markdown frontmatter", Fields{"path": blogMarkdownDirectory})
+		return loadBlogPostsFromFrontmatter(logger, blogMarkdownDirectory, renderer)
+	}
+
+	return loadBlogPostsFromConfig(logger, blogConfigPath, blogMarkdownDirectory, renderer)
+}
+
+// loadBlogPostsFromConfig reads blog-config.json and every markdown file
+// it references from blogMarkdownDirectory, returning the fully rendered
+// set of posts keyed by slug.
+func loadBlogPostsFromConfig(logger Logger, blogConfigPath string, blogMarkdownDirectory string, renderer MarkdownRenderer) (map[string]*BlogPost, error) {
 	logger.Debug("Reading blog config file", Fields{"path": blogConfigPath})
 
 	configFileData, err := ioutil.ReadFile(blogConfigPath)
@@ -106,7 +209,11 @@ func NewInMemoryBlogService(logger Logger, blogMarkdownDirectory string) (*InMem
 			return nil, err
 		}
 
-		blogHtml := string(blackfriday.Run(bytes))
+		blogHtml, headings, err := renderMarkdown(renderer, bytes)
+		if err != nil {
+			logger.Debug("Unable to render blog post markdown", Fields{"path": filePath, "slug": blogPost.Slug, "title": blogPost.Title})
+			return nil, err
+		}
 
 		loadedBlogPost := &BlogPost{
 			Slug:        blogPost.Slug,
@@ -115,19 +222,19 @@ func NewInMemoryBlogService(logger Logger, blogMarkdownDirectory string) (*InMem
 			Image:       blogPost.Image,
 			ImageAlt:    blogPost.ImageAlt,
 			HTMLContent: blogHtml,
+			Headings:    headings,
 		}
 
 		blogPosts[loadedBlogPost.Slug] = loadedBlogPost
 	}
 
-	return &InMemoryBlogService{
-		Logger:          logger,
-		BlogMarkdownDir: blogMarkdownDirectory,
-		BlogPosts:       blogPosts,
-	}, nil
+	return blogPosts, nil
 }
 
 func (bs *InMemoryBlogService) Posts() []*BlogPost {
+	bs.blogPostsMu.RLock()
+	defer bs.blogPostsMu.RUnlock()
+
 	posts := make([]*BlogPost, 0)
 	for _, post := range bs.BlogPosts {
 		posts = append(posts, post)
@@ -136,5 +243,163 @@ func (bs *InMemoryBlogService) Posts() []*BlogPost {
 }
 
 func (bs *InMemoryBlogService) PostBySlug(slug string) *BlogPost {
+	bs.blogPostsMu.RLock()
+	defer bs.blogPostsMu.RUnlock()
+
 	return bs.BlogPosts[slug]
 }
+
+// Start begins watching BlogMarkdownDir for changes and hot-reloading
+// blog posts in the background. Callers should tie this to the HTTP
+// server lifecycle and call Stop when shutting down. If the watcher
+// can't be created, Start returns the error and the service keeps
+// serving whatever was loaded by NewInMemoryBlogService.
+func (bs *InMemoryBlogService) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		bs.Logger.Error("Unable to create blog markdown watcher", Fields{"error": err.Error()})
+		return err
+	}
+
+	if err := watcher.Add(bs.BlogMarkdownDir); err != nil {
+		bs.Logger.Error("Unable to watch blog markdown directory", Fields{"path": bs.BlogMarkdownDir, "error": err.Error()})
+		watcher.Close()
+		return err
+	}
+
+	bs.watcher = watcher
+	bs.stopCh = make(chan struct{})
+	bs.doneCh = make(chan struct{})
+
+	go bs.watchLoop(ctx)
+
+	bs.Logger.Info("Watching blog markdown directory for changes", Fields{"path": bs.BlogMarkdownDir})
+	return nil
+}
+
+// Stop tears down the background watcher started by Start. It is safe
+// to call Stop even if Start was never called or already failed.
+func (bs *InMemoryBlogService) Stop() error {
+	if bs.stopCh == nil {
+		return nil
+	}
+
+	close(bs.stopCh)
+	<-bs.doneCh
+
+	return bs.watcher.Close()
+}
+
+// watchLoop debounces bursts of filesystem events on BlogMarkdownDir
+// before triggering a reload, so that editors writing several files in
+// quick succession (or in multiple passes) only cause one reload.
+func (bs *InMemoryBlogService) watchLoop(ctx context.Context) {
+	defer close(bs.doneCh)
+
+	var debounce *time.Timer
+
+	for {
+		select {
+		case <-bs.stopCh:
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+
+		case <-ctx.Done():
+			if debounce != nil {
+				debounce.Stop()
+			}
+			return
+
+		case event, ok := <-bs.watcher.Events:
+			if !ok {
+				return
+			}
+			bs.Logger.Debug("Blog markdown directory changed", Fields{"event": event.String()})
+
+			if debounce == nil {
+				debounce = time.NewTimer(reloadDebounce)
+			} else {
+				if !debounce.Stop() {
+					<-debounce.C
+				}
+				debounce.Reset(reloadDebounce)
+			}
+
+		case err, ok := <-bs.watcher.Errors:
+			if !ok {
+				return
+			}
+			bs.Logger.Error("Error watching blog markdown directory", Fields{"error": err.Error()})
+
+		case <-debounceC(debounce):
+			bs.reload()
+			debounce = nil
+		}
+	}
+}
+
+// debounceC returns the timer's channel, or nil when no debounce is in
+// flight, so the select above can safely disable that case.
+func debounceC(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
+
+// Reload forces an immediate re-read of BlogMarkdownDir, bypassing the
+// watcher's debounce. Callers that write a new post directly (e.g. the
+// Micropub endpoint) use this to make it live without waiting for the
+// next filesystem event.
+func (bs *InMemoryBlogService) Reload() {
+	bs.reload()
+}
+
+// reload re-reads BlogMarkdownDir via loadBlogPosts (blog-config.json and
+// the markdown files it references when the config exists, otherwise
+// every *.md file's frontmatter), atomically swapping BlogPosts on
+// success. A reload that fails (invalid config or frontmatter, missing
+// markdown file) leaves the last good
+// snapshot live rather than tearing down the service.
+func (bs *InMemoryBlogService) reload() {
+	blogPosts, err := loadBlogPosts(bs.Logger, bs.BlogMarkdownDir, bs.Renderer)
+	if err != nil {
+		bs.Logger.Error("Failed to reload blog posts, keeping last good snapshot", Fields{"path": bs.BlogMarkdownDir, "error": err.Error()})
+		return
+	}
+
+	bs.blogPostsMu.Lock()
+	previousBlogPosts := bs.BlogPosts
+	bs.BlogPosts = blogPosts
+	bs.blogPostsMu.Unlock()
+
+	bs.Logger.Info("Reloaded blog posts", Fields{"path": bs.BlogMarkdownDir, "count": len(blogPosts)})
+
+	for _, hook := range bs.reloadHooks {
+		hook()
+	}
+
+	for slug, post := range blogPosts {
+		if _, existed := previousBlogPosts[slug]; existed {
+			continue
+		}
+		for _, hook := range bs.postHooks {
+			hook(post)
+		}
+	}
+}
+
+// sortPostsByDateDesc sorts posts newest-first by Date, falling back to
+// Slug to keep ordering stable when dates are equal (or zero, as for
+// posts loaded from the legacy blog-config.json).
+func sortPostsByDateDesc(posts []*BlogPost) []*BlogPost {
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].Date.Equal(posts[j].Date) {
+			return posts[i].Date.After(posts[j].Date)
+		}
+		return posts[i].Slug < posts[j].Slug
+	})
+	return posts
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestInMemoryBlogServiceConcurrentAccessDuringSwap exercises Posts() and
+// PostBySlug() concurrently with the same map-swap reload() performs,
+// so that running with -race catches a torn read if blogPostsMu is ever
+// dropped from either side.
+func TestInMemoryBlogServiceConcurrentAccessDuringSwap(t *testing.T) {
+	bs := &InMemoryBlogService{
+		BlogPosts: map[string]*BlogPost{"a": {Slug: "a"}},
+	}
+
+	const iterations = 1000
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			swapped := map[string]*BlogPost{"a": {Slug: "a"}, "b": {Slug: "b"}}
+			bs.blogPostsMu.Lock()
+			bs.BlogPosts = swapped
+			bs.blogPostsMu.Unlock()
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			posts := bs.Posts()
+			if len(posts) == 0 {
+				t.Error("Posts() observed an empty map mid-swap")
+			}
+			if post := bs.PostBySlug("a"); post == nil || post.Slug != "a" {
+				t.Error("PostBySlug(\"a\") did not observe a consistent post")
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+// withShortReloadDebounce shortens reloadDebounce for the duration of a
+// test, so a watcher test doesn't have to wait the real 3s before it can
+// observe a reload.
+func withShortReloadDebounce(t *testing.T) {
+	t.Helper()
+	original := reloadDebounce
+	reloadDebounce = 50 * time.Millisecond
+	t.Cleanup(func() { reloadDebounce = original })
+}
+
+// waitForCondition polls done until it returns true or timeout elapses,
+// failing the test if the condition never becomes true.
+func waitForCondition(t *testing.T, timeout time.Duration, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if done() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !done() {
+		t.Fatal("condition was not met before the timeout")
+	}
+}
+
+func writeTestPost(t *testing.T, dir string, filename string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0600); err != nil {
+		t.Fatalf("writing %s: %v", filename, err)
+	}
+}
+
+func TestInMemoryBlogServiceWatcherReloadsOnNewFile(t *testing.T) {
+	withShortReloadDebounce(t)
+
+	dir := t.TempDir()
+	bs, err := NewInMemoryBlogService(fakeLogger{}, dir, "")
+	if err != nil {
+		t.Fatalf("NewInMemoryBlogService() error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := bs.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer bs.Stop()
+
+	writeTestPost(t, dir, "hello.md", "---\ntitle: Hello\n---\n\nHi there.\n")
+
+	waitForCondition(t, 2*time.Second, func() bool {
+		return bs.PostBySlug("hello") != nil
+	})
+
+	post := bs.PostBySlug("hello")
+	if post == nil || post.Title != "Hello" {
+		t.Fatalf("PostBySlug(\"hello\") = %+v, want a post titled %q", post, "Hello")
+	}
+}
+
+func TestInMemoryBlogServiceWatcherKeepsLastGoodSnapshotOnReloadFailure(t *testing.T) {
+	withShortReloadDebounce(t)
+
+	dir := t.TempDir()
+	writeTestPost(t, dir, "hello.md", "---\ntitle: Hello\n---\n\nHi there.\n")
+
+	bs, err := NewInMemoryBlogService(fakeLogger{}, dir, "")
+	if err != nil {
+		t.Fatalf("NewInMemoryBlogService() error: %v", err)
+	}
+	if post := bs.PostBySlug("hello"); post == nil {
+		t.Fatalf("expected the initial load to contain %q", "hello")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := bs.Start(ctx); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	defer bs.Stop()
+
+	// An unterminated frontmatter block fails loadBlogPosts for the
+	// whole directory, so the reload it triggers must be rejected
+	// rather than dropping the still-good "hello" post.
+	writeTestPost(t, dir, "broken.md", "---\ntitle: Broken\n\nMissing the closing delimiter.\n")
+
+	// There's no success signal to wait on for a reload that's
+	// expected to fail, so just wait out the debounce window and a
+	// margin, then assert the snapshot never changed.
+	time.Sleep(reloadDebounce + 200*time.Millisecond)
+
+	if post := bs.PostBySlug("hello"); post == nil || post.Title != "Hello" {
+		t.Fatalf("PostBySlug(\"hello\") = %+v, want the last good snapshot preserved", post)
+	}
+	if post := bs.PostBySlug("broken"); post != nil {
+		t.Fatalf("PostBySlug(\"broken\") = %+v, want nil since the failed reload should not have been applied", post)
+	}
+}
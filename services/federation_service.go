@@ -0,0 +1,675 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-fed/httpsig"
+)
+
+const (
+	activityStreamsContext  = "https://www.w3.org/ns/activitystreams"
+	actorPrivateKeyFilename = "actor_private_key.pem"
+	outboxPageSize          = 20
+
+	// federationHTTPTimeout bounds every outbound request this service
+	// makes (actor/key fetches, inbox deliveries), so a single slow or
+	// unresponsive remote server can't hang a Micropub POST or the
+	// watcher goroutine.
+	federationHTTPTimeout = 10 * time.Second
+
+	// maxFederationBodyBytes caps how much of a remote actor document or
+	// inbound activity this service will read, regardless of what
+	// Content-Length claims: both are attacker-controlled, since any
+	// actor that can produce a valid HTTP signature may POST to /actor/inbox.
+	maxFederationBodyBytes = 1 << 20 // 1 MiB
+)
+
+// FederationService makes the blog discoverable and subscribable over
+// ActivityPub: the blog itself is exposed as an Actor, and each BlogPost
+// is exposed as a Note/Article in that Actor's outbox. New posts are
+// pushed to known followers' inboxes as Create activities.
+type FederationService struct {
+	Logger      Logger
+	BlogService BlogService
+
+	// BaseURL is the blog's public base URL, e.g. "https://example.com".
+	BaseURL string
+
+	// ActorName is the local part of the blog's acct: handle, e.g. "blog".
+	ActorName string
+
+	privateKey *rsa.PrivateKey
+	store      *followerStore
+
+	// fetchActorKey resolves an HTTP Signature keyId to the remote
+	// actor's RSA public key. Defaults to fetchActorPublicKey; tests
+	// substitute a stub so HandleInbox can be exercised without a real
+	// HTTPS actor endpoint to fetch from.
+	fetchActorKey func(keyID string) (*rsa.PublicKey, error)
+
+	// fetchFollowerActor resolves a follower's actor URL to their actor
+	// document, so deliverToFollower can find its inbox. Defaults to
+	// fetchActor; tests substitute a stub so DeliverNewPost can be
+	// exercised without a real HTTPS actor endpoint to fetch from.
+	fetchFollowerActor func(actorURL string) (*activityPubActor, error)
+
+	// resolveDeliveryTarget validates and pins a follower inbox URL for
+	// delivery, the same way actor fetches are pinned. Defaults to
+	// resolveFederationTargetAddr; tests substitute a stub so delivery
+	// can target a local httptest server without tripping the SSRF
+	// guard that rejects loopback addresses.
+	resolveDeliveryTarget func(rawURL string) (*url.URL, net.IP, error)
+}
+
+func NewFederationService(logger Logger, blogService BlogService, baseURL string, actorName string, dataDir string) (*FederationService, error) {
+	privateKey, err := loadOrCreateActorKey(filepath.Join(dataDir, actorPrivateKeyFilename))
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newFollowerStore(filepath.Join(dataDir, followerStoreFilename))
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &FederationService{
+		Logger:      logger,
+		BlogService: blogService,
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		ActorName:   actorName,
+		privateKey:  privateKey,
+		store:       store,
+	}
+	fs.fetchActorKey = fs.fetchActorPublicKey
+	fs.fetchFollowerActor = fetchActor
+	fs.resolveDeliveryTarget = resolveFederationTargetAddr
+
+	if notifier, ok := blogService.(*InMemoryBlogService); ok {
+		notifier.OnNewPost(fs.DeliverNewPost)
+	}
+
+	return fs, nil
+}
+
+// loadOrCreateActorKey reads the actor's RSA key from path, generating
+// and persisting a new one on first run.
+func loadOrCreateActorKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("invalid PEM data in %s", path)
+		}
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	pemBytes := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+	if err := ioutil.WriteFile(path, pemBytes, 0600); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (fs *FederationService) actorURL() string  { return fs.BaseURL + "/actor" }
+func (fs *FederationService) inboxURL() string  { return fs.actorURL() + "/inbox" }
+func (fs *FederationService) outboxURL() string { return fs.actorURL() + "/outbox" }
+func (fs *FederationService) keyID() string     { return fs.actorURL() + "#main-key" }
+
+// HandleWebFinger serves /.well-known/webfinger?resource=acct:name@host,
+// pointing resolvers at the actor's ActivityPub document.
+func (fs *FederationService) HandleWebFinger(w http.ResponseWriter, r *http.Request) {
+	expected := fmt.Sprintf("acct:%s@%s", fs.ActorName, hostFromBaseURL(fs.BaseURL))
+	if r.URL.Query().Get("resource") != expected {
+		http.Error(w, "resource not found", http.StatusNotFound)
+		return
+	}
+
+	doc := webfingerDocument{
+		Subject: expected,
+		Links: []webfingerLink{
+			{Rel: "self", Type: "application/activity+json", Href: fs.actorURL()},
+		},
+	}
+
+	fs.writeJSON(w, "application/jrd+json", doc)
+}
+
+func hostFromBaseURL(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	return u.Host
+}
+
+// HandleActor serves GET /actor: the blog's ActivityPub Actor document.
+func (fs *FederationService) HandleActor(w http.ResponseWriter, r *http.Request) {
+	pubKeyBytes, err := x509.MarshalPKIXPublicKey(&fs.privateKey.PublicKey)
+	if err != nil {
+		fs.Logger.Error("Unable to marshal actor public key", Fields{"error": err.Error()})
+		http.Error(w, "unable to encode public key", http.StatusInternalServerError)
+		return
+	}
+	pubKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubKeyBytes})
+
+	actor := activityPubActor{
+		Context:           []string{activityStreamsContext, "https://w3id.org/security/v1"},
+		ID:                fs.actorURL(),
+		Type:              "Person",
+		PreferredUsername: fs.ActorName,
+		Inbox:             fs.inboxURL(),
+		Outbox:            fs.outboxURL(),
+		PublicKey: activityPubPublicKey{
+			ID:           fs.keyID(),
+			Owner:        fs.actorURL(),
+			PublicKeyPem: string(pubKeyPEM),
+		},
+	}
+
+	fs.writeJSON(w, "application/activity+json", actor)
+}
+
+// HandleOutbox serves GET /actor/outbox, paging over BlogService.Posts()
+// as Create activities, newest first.
+func (fs *FederationService) HandleOutbox(w http.ResponseWriter, r *http.Request) {
+	posts := fs.sortedPosts()
+
+	pageParam := r.URL.Query().Get("page")
+	if pageParam == "" {
+		collection := activityPubCollection{
+			Context:    activityStreamsContext,
+			ID:         fs.outboxURL(),
+			Type:       "OrderedCollection",
+			TotalItems: len(posts),
+			First:      fs.outboxURL() + "?page=1",
+		}
+		fs.writeJSON(w, "application/activity+json", collection)
+		return
+	}
+
+	page, err := strconv.Atoi(pageParam)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	start := (page - 1) * outboxPageSize
+	if start > len(posts) {
+		start = len(posts)
+	}
+	end := start + outboxPageSize
+	if end > len(posts) {
+		end = len(posts)
+	}
+
+	items := make([]interface{}, 0, end-start)
+	for _, post := range posts[start:end] {
+		items = append(items, fs.createActivityForPost(post))
+	}
+
+	collectionPage := activityPubCollectionPage{
+		Context:      activityStreamsContext,
+		ID:           fmt.Sprintf("%s?page=%d", fs.outboxURL(), page),
+		Type:         "OrderedCollectionPage",
+		PartOf:       fs.outboxURL(),
+		OrderedItems: items,
+	}
+	if end < len(posts) {
+		collectionPage.Next = fmt.Sprintf("%s?page=%d", fs.outboxURL(), page+1)
+	}
+
+	fs.writeJSON(w, "application/activity+json", collectionPage)
+}
+
+func (fs *FederationService) sortedPosts() []*BlogPost {
+	return sortPostsByDateDesc(fs.BlogService.Posts())
+}
+
+func (fs *FederationService) noteForPost(post *BlogPost) activityPubNote {
+	id := fmt.Sprintf("%s/blog/%s", fs.BaseURL, post.Slug)
+	return activityPubNote{
+		ID:           id,
+		Type:         "Article",
+		AttributedTo: fs.actorURL(),
+		Name:         post.Title,
+		Content:      post.HTMLContent,
+		Published:    post.Date.UTC().Format(time.RFC3339),
+		URL:          id,
+	}
+}
+
+func (fs *FederationService) createActivityForPost(post *BlogPost) activityPubCreateActivity {
+	note := fs.noteForPost(post)
+	return activityPubCreateActivity{
+		Context: activityStreamsContext,
+		ID:      note.ID + "/activity",
+		Type:    "Create",
+		Actor:   fs.actorURL(),
+		Object:  note,
+	}
+}
+
+// HandleInbox serves POST /actor/inbox: it verifies the sender's HTTP
+// Signature against their published actor key, then stores Follow and
+// Like activities for later delivery and lookups, and removes the
+// sender's follower record on an Undo{Follow}.
+func (fs *FederationService) HandleInbox(w http.ResponseWriter, r *http.Request) {
+	verifier, err := httpsig.NewVerifier(r)
+	if err != nil {
+		fs.Logger.Error("Unable to parse HTTP signature", Fields{"error": err.Error()})
+		http.Error(w, "missing or invalid signature", http.StatusBadRequest)
+		return
+	}
+
+	senderPublicKey, err := fs.fetchActorKey(verifier.KeyId())
+	if err != nil {
+		fs.Logger.Error("Unable to fetch sender public key", Fields{"keyId": verifier.KeyId(), "error": err.Error()})
+		http.Error(w, "unable to verify signature", http.StatusForbidden)
+		return
+	}
+
+	if err := verifier.Verify(senderPublicKey, httpsig.RSA_SHA256); err != nil {
+		fs.Logger.Error("HTTP signature verification failed", Fields{"keyId": verifier.KeyId(), "error": err.Error()})
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	activity := &activityPubActivity{}
+	body := io.LimitReader(r.Body, maxFederationBodyBytes)
+	if err := json.NewDecoder(body).Decode(activity); err != nil {
+		http.Error(w, "invalid activity", http.StatusBadRequest)
+		return
+	}
+
+	// The HTTP Signature only proves the request was signed by keyId's
+	// actor; without this check any actor with a valid key could submit
+	// an activity claiming to be a different actor entirely (e.g. an
+	// Undo that deletes a victim's real follow record).
+	if signingActor := actorURLFromKeyID(verifier.KeyId()); activity.Actor != signingActor {
+		fs.Logger.Error("Inbox activity actor does not match signing key", Fields{"keyId": verifier.KeyId(), "actor": activity.Actor})
+		http.Error(w, "activity actor does not match signing key", http.StatusForbidden)
+		return
+	}
+
+	var storeErr error
+	switch activity.Type {
+	case "Follow":
+		storeErr = fs.store.AddFollower(activity.Actor, activity)
+	case "Undo":
+		// Only an Undo{Follow} (an unfollow) should remove the follower
+		// record; an Undo{Like} must not, or any follower could erase
+		// their Follow just by undoing an unrelated Like.
+		if undoObjectType(activity.Object) == "Follow" {
+			storeErr = fs.store.RemoveFollower(activity.Actor)
+		} else {
+			fs.Logger.Debug("Ignoring Undo for non-Follow object", Fields{"actor": activity.Actor})
+		}
+	case "Like":
+		storeErr = fs.store.AddLike(activity.Actor, activity)
+	default:
+		fs.Logger.Debug("Ignoring unsupported inbox activity", Fields{"type": activity.Type})
+	}
+	if storeErr != nil {
+		fs.Logger.Error("Unable to store inbox activity", Fields{"type": activity.Type, "actor": activity.Actor, "error": storeErr.Error()})
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// undoObjectType returns the "type" field of an Undo activity's nested
+// object (e.g. "Follow" or "Like"), so HandleInbox can tell what
+// relationship is being undone instead of treating every Undo alike.
+func undoObjectType(object interface{}) string {
+	obj, ok := object.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	objType, _ := obj["type"].(string)
+	return objType
+}
+
+// actorURLFromKeyID strips the #fragment from an HTTP Signature keyId,
+// returning the actor URL it identifies.
+func actorURLFromKeyID(keyID string) string {
+	return strings.SplitN(keyID, "#", 2)[0]
+}
+
+// fetchActorPublicKey resolves a `keyId` (an actor URL with a #fragment)
+// to the RSA public key published in that actor's document.
+func (fs *FederationService) fetchActorPublicKey(keyID string) (*rsa.PublicKey, error) {
+	actor, err := fetchActor(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(actor.PublicKey.PublicKeyPem))
+	if block == nil {
+		return nil, errors.New("remote actor did not return a PEM public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("remote actor public key is not RSA")
+	}
+
+	return rsaPub, nil
+}
+
+func fetchActor(actorOrKeyID string) (*activityPubActor, error) {
+	actorURL := actorURLFromKeyID(actorOrKeyID)
+
+	parsed, pinnedIP, err := resolveFederationTargetAddr(actorURL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/activity+json")
+
+	resp, err := pinnedHTTPClient(pinnedIP).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching actor %s returned status %d", actorURL, resp.StatusCode)
+	}
+
+	actor := &activityPubActor{}
+	body := io.LimitReader(resp.Body, maxFederationBodyBytes)
+	if err := json.NewDecoder(body).Decode(actor); err != nil {
+		return nil, err
+	}
+
+	return actor, nil
+}
+
+// resolveFederationTargetAddr validates that rawURL is a plain https URL
+// to a public host, and resolves it to a single allowed IP to connect
+// to. It backs every outbound federation request (actor/key fetches,
+// inbox deliveries): both the follower-supplied actor URL from HandleInbox
+// and the inbox URL a remote actor document hands back in DeliverNewPost
+// are attacker-influenced, so neither may reach loopback/private/
+// link-local addresses.
+//
+// The returned IP must be used to dial the connection directly (see
+// pinnedHTTPClient) rather than left for the HTTP client to resolve
+// again: a second, independent DNS lookup at connect time re-opens a
+// DNS-rebinding gap, since an attacker's domain can answer the
+// validation lookup with a public address and a later one with a
+// private address.
+func resolveFederationTargetAddr(rawURL string) (*url.URL, net.IP, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if parsed.Scheme != "https" {
+		return nil, nil, fmt.Errorf("URL %q must use https", rawURL)
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return nil, nil, fmt.Errorf("URL %q has no host", rawURL)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if isDisallowedRemoteIP(ip) {
+			return nil, nil, fmt.Errorf("URL %q resolves to a disallowed address", rawURL)
+		}
+		return parsed, ip, nil
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to resolve host %q: %w", host, err)
+	}
+	for _, ip := range ips {
+		if !isDisallowedRemoteIP(ip) {
+			return parsed, ip, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("URL %q has no allowed address", rawURL)
+}
+
+// isDisallowedRemoteIP reports whether ip is loopback, link-local, or
+// otherwise private and so must not be reachable as a federation target.
+func isDisallowedRemoteIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast()
+}
+
+// pinnedHTTPClient returns an HTTP client whose dialer always connects to
+// pinnedIP regardless of what the request's hostname resolves to at dial
+// time, so resolveFederationTargetAddr's validation is authoritative
+// instead of racing a second DNS lookup.
+func pinnedHTTPClient(pinnedIP net.IP) *http.Client {
+	dialer := &net.Dialer{}
+	return &http.Client{
+		Timeout: federationHTTPTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				_, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				return dialer.DialContext(ctx, network, net.JoinHostPort(pinnedIP.String(), port))
+			},
+		},
+	}
+}
+
+// DeliverNewPost signs and delivers a Create{Article} activity for post
+// to every known follower's inbox. NewFederationService registers it as
+// an InMemoryBlogService.OnNewPost hook, so it fires automatically after
+// a hot-reload surfaces a new post.
+//
+// OnNewPost hooks run synchronously on the caller's goroutine (the
+// watcher, or the Micropub HTTP handler via Reload), so each follower's
+// delivery is dispatched to its own goroutine: a single slow or
+// unresponsive inbox must not block the caller, and followers are
+// delivered to independently of one another.
+func (fs *FederationService) DeliverNewPost(post *BlogPost) {
+	activity := fs.createActivityForPost(post)
+	body, err := json.Marshal(activity)
+	if err != nil {
+		fs.Logger.Error("Unable to marshal Create activity", Fields{"slug": post.Slug, "error": err.Error()})
+		return
+	}
+
+	followers, err := fs.store.Followers()
+	if err != nil {
+		fs.Logger.Error("Unable to list followers", Fields{"error": err.Error()})
+		return
+	}
+
+	for _, follower := range followers {
+		go fs.deliverToFollower(post, follower, body)
+	}
+}
+
+// deliverToFollower resolves a single follower's inbox and delivers body
+// to it, logging (rather than returning) any failure since it runs on
+// its own goroutine with no caller left to hand an error to.
+func (fs *FederationService) deliverToFollower(post *BlogPost, follower string, body []byte) {
+	actor, err := fs.fetchFollowerActor(follower)
+	if err != nil {
+		fs.Logger.Error("Unable to resolve follower inbox", Fields{"follower": follower, "error": err.Error()})
+		return
+	}
+
+	if err := fs.deliverSigned(actor.Inbox, body); err != nil {
+		fs.Logger.Error("Unable to deliver activity to follower", Fields{"follower": follower, "inbox": actor.Inbox, "error": err.Error()})
+		return
+	}
+
+	fs.Logger.Info("Delivered new post to follower", Fields{"slug": post.Slug, "follower": follower})
+}
+
+// deliverSigned validates and pins inboxURL the same way fetchActor
+// does before delivering to it: inboxURL comes from a remote actor
+// document (actor.Inbox), which a successfully-followed account fully
+// controls, so it's just as attacker-influenced as the actor URLs
+// fetchActor resolves.
+func (fs *FederationService) deliverSigned(inboxURL string, body []byte) error {
+	parsed, pinnedIP, err := fs.resolveDeliveryTarget(inboxURL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, parsed.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/activity+json")
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Host", req.URL.Host)
+
+	signer, _, err := httpsig.NewSigner(
+		[]httpsig.Algorithm{httpsig.RSA_SHA256},
+		httpsig.DigestSha256,
+		[]string{httpsig.RequestTarget, "host", "date", "digest"},
+		httpsig.Signature,
+		0,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := signer.SignRequest(fs.privateKey, fs.keyID(), req, body); err != nil {
+		return err
+	}
+
+	resp, err := pinnedHTTPClient(pinnedIP).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("inbox delivery to %s returned status %d", inboxURL, resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (fs *FederationService) writeJSON(w http.ResponseWriter, contentType string, v interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fs.Logger.Error("Unable to encode federation response", Fields{"error": err.Error()})
+	}
+}
+
+type webfingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type"`
+	Href string `json:"href"`
+}
+
+type webfingerDocument struct {
+	Subject string          `json:"subject"`
+	Links   []webfingerLink `json:"links"`
+}
+
+type activityPubPublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type activityPubActor struct {
+	Context           []string             `json:"@context"`
+	ID                string               `json:"id"`
+	Type              string               `json:"type"`
+	PreferredUsername string               `json:"preferredUsername"`
+	Inbox             string               `json:"inbox"`
+	Outbox            string               `json:"outbox"`
+	PublicKey         activityPubPublicKey `json:"publicKey"`
+}
+
+type activityPubNote struct {
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	AttributedTo string `json:"attributedTo"`
+	Name         string `json:"name"`
+	Content      string `json:"content"`
+	Published    string `json:"published"`
+	URL          string `json:"url"`
+}
+
+type activityPubCreateActivity struct {
+	Context string          `json:"@context"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor"`
+	Object  activityPubNote `json:"object"`
+}
+
+type activityPubCollection struct {
+	Context    string `json:"@context"`
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	TotalItems int    `json:"totalItems"`
+	First      string `json:"first"`
+}
+
+type activityPubCollectionPage struct {
+	Context      string        `json:"@context"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	PartOf       string        `json:"partOf"`
+	OrderedItems []interface{} `json:"orderedItems"`
+	Next         string        `json:"next,omitempty"`
+}
+
+// activityPubActivity is the subset of an incoming inbox activity this
+// service understands (Follow/Undo/Like); Object is left loosely typed
+// since its shape varies by activity type.
+type activityPubActivity struct {
+	Context string      `json:"@context"`
+	ID      string      `json:"id"`
+	Type    string      `json:"type"`
+	Actor   string      `json:"actor"`
+	Object  interface{} `json:"object"`
+}
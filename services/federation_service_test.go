@@ -0,0 +1,378 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-fed/httpsig"
+)
+
+func newTestFederationService(t *testing.T) *FederationService {
+	t.Helper()
+	posts := []*BlogPost{{Slug: "hello", Title: "Hello", HTMLContent: "<p>Hi</p>", Date: time.Unix(0, 0)}}
+	fs, err := NewFederationService(fakeLogger{}, stubBlogService{posts: posts}, "https://blog.example.com", "blog", t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFederationService() error: %v", err)
+	}
+	return fs
+}
+
+// signedInboxRequest builds a POST /actor/inbox request for activity,
+// signed with signerKey under keyID, the same way deliverSigned signs
+// outgoing deliveries - so HandleInbox's verifier has a real signature
+// to check.
+func signedInboxRequest(t *testing.T, signerKey *rsa.PrivateKey, keyID string, activity activityPubActivity) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(activity)
+	if err != nil {
+		t.Fatalf("marshal activity: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://blog.example.com/actor/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/activity+json")
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("Host", req.URL.Host)
+
+	signer, _, err := httpsig.NewSigner(
+		[]httpsig.Algorithm{httpsig.RSA_SHA256},
+		httpsig.DigestSha256,
+		[]string{httpsig.RequestTarget, "host", "date", "digest"},
+		httpsig.Signature,
+		0,
+	)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	if err := signer.SignRequest(signerKey, keyID, req, body); err != nil {
+		t.Fatalf("sign request: %v", err)
+	}
+	return req
+}
+
+func generateTestActorKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func TestActorURLFromKeyID(t *testing.T) {
+	got := actorURLFromKeyID("https://example.com/actor#main-key")
+	want := "https://example.com/actor"
+	if got != want {
+		t.Errorf("actorURLFromKeyID() = %q, want %q", got, want)
+	}
+}
+
+func TestHostFromBaseURL(t *testing.T) {
+	got := hostFromBaseURL("https://blog.example.com")
+	want := "blog.example.com"
+	if got != want {
+		t.Errorf("hostFromBaseURL() = %q, want %q", got, want)
+	}
+}
+
+func TestIsDisallowedRemoteIP(t *testing.T) {
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"169.254.169.254", true}, // cloud metadata endpoint
+		{"10.0.0.5", true},
+		{"192.168.1.1", true},
+		{"0.0.0.0", true},
+		{"93.184.216.34", false}, // a public address
+		{"8.8.8.8", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.ip, func(t *testing.T) {
+			got := isDisallowedRemoteIP(net.ParseIP(tc.ip))
+			if got != tc.want {
+				t.Errorf("isDisallowedRemoteIP(%s) = %v, want %v", tc.ip, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolveFederationTargetAddr(t *testing.T) {
+	t.Run("rejects non-https schemes", func(t *testing.T) {
+		if _, _, err := resolveFederationTargetAddr("http://93.184.216.34/actor"); err == nil {
+			t.Fatal("expected an error for a non-https URL")
+		}
+	})
+
+	t.Run("rejects loopback addresses", func(t *testing.T) {
+		if _, _, err := resolveFederationTargetAddr("https://127.0.0.1/actor"); err == nil {
+			t.Fatal("expected an error for a loopback address")
+		}
+	})
+
+	t.Run("rejects cloud metadata addresses", func(t *testing.T) {
+		if _, _, err := resolveFederationTargetAddr("https://169.254.169.254/latest/meta-data/"); err == nil {
+			t.Fatal("expected an error for a link-local address")
+		}
+	})
+
+	t.Run("rejects private addresses", func(t *testing.T) {
+		if _, _, err := resolveFederationTargetAddr("https://10.0.0.5/inbox"); err == nil {
+			t.Fatal("expected an error for a private address")
+		}
+	})
+
+	t.Run("accepts a public https address", func(t *testing.T) {
+		parsed, ip, err := resolveFederationTargetAddr("https://93.184.216.34/actor")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if parsed.String() != "https://93.184.216.34/actor" {
+			t.Errorf("parsed URL = %q, want the original URL unchanged", parsed.String())
+		}
+		if ip.String() != "93.184.216.34" {
+			t.Errorf("resolved IP = %q, want %q", ip.String(), "93.184.216.34")
+		}
+	})
+}
+
+// TestPinnedHTTPClientDialsPinnedAddress proves pinnedHTTPClient ignores
+// the request URL's hostname for DNS resolution and always connects to
+// the IP it was given, which is what closes the DNS-rebinding gap
+// between resolveFederationTargetAddr's validation and the connection a
+// plain http.Client would otherwise re-resolve independently.
+func TestPinnedHTTPClientDialsPinnedAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tcpAddr := server.Listener.Addr().(*net.TCPAddr)
+	client := pinnedHTTPClient(tcpAddr.IP)
+
+	// This hostname does not exist; if pinnedHTTPClient resolved it
+	// independently instead of dialing the pinned IP, this request
+	// would fail with a DNS lookup error.
+	bogusURL := fmt.Sprintf("http://this-host-should-never-be-resolved.invalid:%d/", tcpAddr.Port)
+
+	resp, err := client.Get(bogusURL)
+	if err != nil {
+		t.Fatalf("unexpected error dialing pinned address: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestHandleInboxFollowThenUndoRemovesFollower(t *testing.T) {
+	fs := newTestFederationService(t)
+	signerKey := generateTestActorKey(t)
+	keyID := "https://remote.example/actor#main-key"
+	actor := "https://remote.example/actor"
+	fs.fetchActorKey = func(string) (*rsa.PublicKey, error) { return &signerKey.PublicKey, nil }
+
+	follow := activityPubActivity{Type: "Follow", Actor: actor, Object: fs.actorURL()}
+	rec := httptest.NewRecorder()
+	fs.HandleInbox(rec, signedInboxRequest(t, signerKey, keyID, follow))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Follow: status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	followers, err := fs.store.Followers()
+	if err != nil {
+		t.Fatalf("Followers() error: %v", err)
+	}
+	if !containsString(followers, actor) {
+		t.Fatalf("Followers() = %v, want it to contain %q after a Follow", followers, actor)
+	}
+
+	undo := activityPubActivity{Type: "Undo", Actor: actor, Object: map[string]interface{}{"type": "Follow"}}
+	rec = httptest.NewRecorder()
+	fs.HandleInbox(rec, signedInboxRequest(t, signerKey, keyID, undo))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Undo{Follow}: status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	followers, err = fs.store.Followers()
+	if err != nil {
+		t.Fatalf("Followers() error: %v", err)
+	}
+	if containsString(followers, actor) {
+		t.Fatalf("Followers() = %v, want %q removed after Undo{Follow}", followers, actor)
+	}
+}
+
+func TestHandleInboxUndoLikeDoesNotRemoveFollower(t *testing.T) {
+	fs := newTestFederationService(t)
+	signerKey := generateTestActorKey(t)
+	keyID := "https://remote.example/actor#main-key"
+	actor := "https://remote.example/actor"
+	fs.fetchActorKey = func(string) (*rsa.PublicKey, error) { return &signerKey.PublicKey, nil }
+
+	follow := activityPubActivity{Type: "Follow", Actor: actor, Object: fs.actorURL()}
+	rec := httptest.NewRecorder()
+	fs.HandleInbox(rec, signedInboxRequest(t, signerKey, keyID, follow))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Follow: status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	undoLike := activityPubActivity{Type: "Undo", Actor: actor, Object: map[string]interface{}{"type": "Like"}}
+	rec = httptest.NewRecorder()
+	fs.HandleInbox(rec, signedInboxRequest(t, signerKey, keyID, undoLike))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Undo{Like}: status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	followers, err := fs.store.Followers()
+	if err != nil {
+		t.Fatalf("Followers() error: %v", err)
+	}
+	if !containsString(followers, actor) {
+		t.Fatalf("Followers() = %v, want %q to remain after an unrelated Undo{Like}", followers, actor)
+	}
+}
+
+func TestHandleInboxRejectsActorMismatch(t *testing.T) {
+	fs := newTestFederationService(t)
+	signerKey := generateTestActorKey(t)
+	keyID := "https://remote.example/actor#main-key"
+	fs.fetchActorKey = func(string) (*rsa.PublicKey, error) { return &signerKey.PublicKey, nil }
+
+	// activity.Actor claims to be a different actor than the one that
+	// signed the request.
+	follow := activityPubActivity{Type: "Follow", Actor: "https://victim.example/actor", Object: fs.actorURL()}
+	rec := httptest.NewRecorder()
+	fs.HandleInbox(rec, signedInboxRequest(t, signerKey, keyID, follow))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	followers, err := fs.store.Followers()
+	if err != nil {
+		t.Fatalf("Followers() error: %v", err)
+	}
+	if len(followers) != 0 {
+		t.Fatalf("Followers() = %v, want no follower recorded for a mismatched actor", followers)
+	}
+}
+
+func TestHandleInboxRejectsOversizedBody(t *testing.T) {
+	fs := newTestFederationService(t)
+	signerKey := generateTestActorKey(t)
+	keyID := "https://remote.example/actor#main-key"
+	actor := "https://remote.example/actor"
+	fs.fetchActorKey = func(string) (*rsa.PublicKey, error) { return &signerKey.PublicKey, nil }
+
+	oversized := activityPubActivity{
+		Type:   "Follow",
+		Actor:  actor,
+		Object: strings.Repeat("a", maxFederationBodyBytes),
+	}
+	rec := httptest.NewRecorder()
+	fs.HandleInbox(rec, signedInboxRequest(t, signerKey, keyID, oversized))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// TestDeliverNewPostSignsAndPostsToFollowerInbox exercises the outbound
+// delivery path end to end: it proves DeliverNewPost builds a Create
+// activity for post, signs the request with the service's own private
+// key, and POSTs it to each follower's inbox. fetchFollowerActor and
+// resolveDeliveryTarget are stubbed only to point delivery at a local
+// httptest.Server instead of a real HTTPS actor endpoint; deliverSigned
+// itself runs unmodified.
+func TestDeliverNewPostSignsAndPostsToFollowerInbox(t *testing.T) {
+	fs := newTestFederationService(t)
+
+	var (
+		mu            sync.Mutex
+		deliveredReq  *http.Request
+		deliveredBody []byte
+	)
+	received := make(chan struct{}, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := new(bytes.Buffer)
+		if _, err := buf.ReadFrom(r.Body); err != nil {
+			t.Errorf("reading delivered body: %v", err)
+		}
+		body := buf.Bytes()
+
+		mu.Lock()
+		deliveredReq = r
+		deliveredBody = body
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusAccepted)
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	follower := "https://follower.example/actor"
+	if err := fs.store.AddFollower(follower, &activityPubActivity{Type: "Follow", Actor: follower}); err != nil {
+		t.Fatalf("AddFollower() error: %v", err)
+	}
+
+	fs.fetchFollowerActor = func(actorURL string) (*activityPubActor, error) {
+		return &activityPubActor{ID: actorURL, Inbox: server.URL + "/inbox"}, nil
+	}
+	fs.resolveDeliveryTarget = func(rawURL string) (*url.URL, net.IP, error) {
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return parsed, net.ParseIP("127.0.0.1"), nil
+	}
+
+	post := &BlogPost{Slug: "hello", Title: "Hello", HTMLContent: "<p>Hi</p>", Date: time.Unix(0, 0)}
+	fs.DeliverNewPost(post)
+
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for delivery to the follower's inbox")
+	}
+
+	mu.Lock()
+	req, body := deliveredReq, deliveredBody
+	mu.Unlock()
+
+	verifier, err := httpsig.NewVerifier(req)
+	if err != nil {
+		t.Fatalf("parsing delivered signature: %v", err)
+	}
+	if err := verifier.Verify(&fs.privateKey.PublicKey, httpsig.RSA_SHA256); err != nil {
+		t.Fatalf("delivered request signature did not verify against the service's own key: %v", err)
+	}
+
+	var delivered activityPubCreateActivity
+	if err := json.Unmarshal(body, &delivered); err != nil {
+		t.Fatalf("unmarshal delivered activity: %v", err)
+	}
+	if delivered.Type != "Create" {
+		t.Errorf("delivered activity type = %q, want %q", delivered.Type, "Create")
+	}
+	if delivered.Object.Name != post.Title {
+		t.Errorf("delivered object name = %q, want %q", delivered.Object.Name, post.Title)
+	}
+}
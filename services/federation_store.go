@@ -0,0 +1,86 @@
+package services
+
+import (
+	"encoding/json"
+
+	"go.etcd.io/bbolt"
+)
+
+const (
+	followerStoreFilename = "federation.db"
+	followersBucket       = "followers"
+	likesBucket           = "likes"
+)
+
+// followerStore persists the Follow/Like activities FederationService
+// receives in its inbox, so followers survive a restart and don't need
+// to be rediscovered before a new post can be delivered to them.
+type followerStore struct {
+	db *bbolt.DB
+}
+
+func newFollowerStore(path string) (*followerStore, error) {
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(followersBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(likesBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &followerStore{db: db}, nil
+}
+
+// AddFollower records actor as a follower, keyed on their actor URL, so
+// a later Undo from the same actor can remove them again.
+func (s *followerStore) AddFollower(actor string, activity *activityPubActivity) error {
+	return s.put(followersBucket, actor, activity)
+}
+
+// RemoveFollower deletes actor's follower record, in response to an
+// Undo{Follow} activity.
+func (s *followerStore) RemoveFollower(actor string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(followersBucket)).Delete([]byte(actor))
+	})
+}
+
+// AddLike records a Like activity from actor.
+func (s *followerStore) AddLike(actor string, activity *activityPubActivity) error {
+	return s.put(likesBucket, actor, activity)
+}
+
+func (s *followerStore) put(bucket string, key string, activity *activityPubActivity) error {
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Put([]byte(key), data)
+	})
+}
+
+// Followers returns the actor URL of every known follower.
+func (s *followerStore) Followers() ([]string, error) {
+	var followers []string
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(followersBucket)).ForEach(func(k, v []byte) error {
+			followers = append(followers, string(k))
+			return nil
+		})
+	})
+	return followers, err
+}
+
+func (s *followerStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFollowerStoreAddAndRemoveFollower(t *testing.T) {
+	store, err := newFollowerStore(filepath.Join(t.TempDir(), "federation.db"))
+	if err != nil {
+		t.Fatalf("newFollowerStore() error: %v", err)
+	}
+	defer store.Close()
+
+	actor := "https://example.com/actor"
+	activity := &activityPubActivity{Type: "Follow", Actor: actor}
+
+	if err := store.AddFollower(actor, activity); err != nil {
+		t.Fatalf("AddFollower() error: %v", err)
+	}
+
+	followers, err := store.Followers()
+	if err != nil {
+		t.Fatalf("Followers() error: %v", err)
+	}
+	if !containsString(followers, actor) {
+		t.Fatalf("Followers() = %v, want it to contain %q", followers, actor)
+	}
+
+	if err := store.RemoveFollower(actor); err != nil {
+		t.Fatalf("RemoveFollower() error: %v", err)
+	}
+
+	followers, err = store.Followers()
+	if err != nil {
+		t.Fatalf("Followers() error: %v", err)
+	}
+	if containsString(followers, actor) {
+		t.Fatalf("Followers() = %v, want %q to have been removed", followers, actor)
+	}
+}
+
+func TestFollowerStoreAddLike(t *testing.T) {
+	store, err := newFollowerStore(filepath.Join(t.TempDir(), "federation.db"))
+	if err != nil {
+		t.Fatalf("newFollowerStore() error: %v", err)
+	}
+	defer store.Close()
+
+	activity := &activityPubActivity{Type: "Like", Actor: "https://example.com/actor"}
+	if err := store.AddLike(activity.Actor, activity); err != nil {
+		t.Fatalf("AddLike() error: %v", err)
+	}
+}
+
+func TestFollowerStorePersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "federation.db")
+	actor := "https://example.com/actor"
+
+	store, err := newFollowerStore(path)
+	if err != nil {
+		t.Fatalf("newFollowerStore() error: %v", err)
+	}
+	if err := store.AddFollower(actor, &activityPubActivity{Type: "Follow", Actor: actor}); err != nil {
+		t.Fatalf("AddFollower() error: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	reopened, err := newFollowerStore(path)
+	if err != nil {
+		t.Fatalf("newFollowerStore() on reopen error: %v", err)
+	}
+	defer reopened.Close()
+
+	followers, err := reopened.Followers()
+	if err != nil {
+		t.Fatalf("Followers() error: %v", err)
+	}
+	if !containsString(followers, actor) {
+		t.Fatalf("Followers() after reopen = %v, want it to still contain %q", followers, actor)
+	}
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
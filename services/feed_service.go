@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/feeds"
+)
+
+// BlogrollFilename is the blogroll.json file, a sibling of
+// BlogConfigFilename, listing the external feeds served at /blogroll.opml.
+const BlogrollFilename = "blogroll.json"
+
+// FeedService generates RSS, Atom and JSON Feed representations of a
+// BlogService's posts, plus an OPML export of a configured blogroll.
+// Rendered feed bodies are cached in memory until invalidated.
+type FeedService struct {
+	Logger      Logger
+	BlogService BlogService
+
+	// BlogMarkdownDir is where blogroll.json is read from.
+	BlogMarkdownDir string
+
+	// BaseURL is the blog's public base URL, e.g. "https://example.com".
+	BaseURL string
+
+	Title       string
+	Description string
+	Author      string
+
+	cacheMu sync.RWMutex
+	cache   map[string]string
+}
+
+func NewFeedService(logger Logger, blogService BlogService, blogMarkdownDir string, baseURL string, title string, description string, author string) *FeedService {
+	feedService := &FeedService{
+		Logger:          logger,
+		BlogService:     blogService,
+		BlogMarkdownDir: blogMarkdownDir,
+		BaseURL:         strings.TrimRight(baseURL, "/"),
+		Title:           title,
+		Description:     description,
+		Author:          author,
+		cache:           make(map[string]string),
+	}
+
+	if notifier, ok := blogService.(*InMemoryBlogService); ok {
+		notifier.OnReload(feedService.InvalidateCache)
+	}
+
+	return feedService
+}
+
+// InvalidateCache drops every cached feed body so the next request for
+// each format re-renders it from the current posts.
+func (fs *FeedService) InvalidateCache() {
+	fs.cacheMu.Lock()
+	fs.cache = make(map[string]string)
+	fs.cacheMu.Unlock()
+}
+
+func (fs *FeedService) buildFeed() *feeds.Feed {
+	posts := sortPostsByDateDesc(fs.BlogService.Posts())
+
+	feed := &feeds.Feed{
+		Title:       fs.Title,
+		Link:        &feeds.Link{Href: fs.BaseURL},
+		Description: fs.Description,
+		Author:      &feeds.Author{Name: fs.Author},
+	}
+	if len(posts) > 0 {
+		feed.Updated = posts[0].Date
+	}
+
+	for _, post := range posts {
+		feed.Items = append(feed.Items, &feeds.Item{
+			Id:          post.Slug,
+			Title:       post.Title,
+			Link:        &feeds.Link{Href: fs.BaseURL + "/blog/" + post.Slug},
+			Description: post.Summary,
+			Content:     post.HTMLContent,
+			Author:      &feeds.Author{Name: fs.Author},
+			Created:     post.Date,
+		})
+	}
+
+	return feed
+}
+
+// HandleRSS serves GET /feed.rss.
+func (fs *FeedService) HandleRSS(w http.ResponseWriter, r *http.Request) {
+	fs.serveCached(w, "rss", "application/rss+xml; charset=utf-8", func() (string, error) {
+		return fs.buildFeed().ToRss()
+	})
+}
+
+// HandleAtom serves GET /feed.atom.
+func (fs *FeedService) HandleAtom(w http.ResponseWriter, r *http.Request) {
+	fs.serveCached(w, "atom", "application/atom+xml; charset=utf-8", func() (string, error) {
+		return fs.buildFeed().ToAtom()
+	})
+}
+
+// HandleJSON serves GET /feed.json (JSON Feed 1.1).
+func (fs *FeedService) HandleJSON(w http.ResponseWriter, r *http.Request) {
+	fs.serveCached(w, "json", "application/feed+json; charset=utf-8", func() (string, error) {
+		return fs.buildFeed().ToJSON()
+	})
+}
+
+// serveCached serves cacheKey's rendered body, calling render to
+// populate the cache on a miss. Every generated feed body (RSS, Atom,
+// JSON Feed, blogroll OPML) goes through this cache, invalidated as a
+// whole by InvalidateCache on the next hot-reload.
+func (fs *FeedService) serveCached(w http.ResponseWriter, cacheKey string, contentType string, render func() (string, error)) {
+	fs.cacheMu.RLock()
+	body, ok := fs.cache[cacheKey]
+	fs.cacheMu.RUnlock()
+
+	if !ok {
+		rendered, err := render()
+		if err != nil {
+			fs.Logger.Error("Unable to render feed", Fields{"feed": cacheKey, "error": err.Error()})
+			http.Error(w, "unable to render feed", http.StatusInternalServerError)
+			return
+		}
+
+		fs.cacheMu.Lock()
+		fs.cache[cacheKey] = rendered
+		fs.cacheMu.Unlock()
+
+		body = rendered
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write([]byte(body))
+}
+
+// BlogrollConfig is the shape of blogroll.json: the external feeds
+// listed on the blogroll.
+type BlogrollConfig struct {
+	Feeds []*BlogrollFeed `json:"feeds"`
+}
+
+type BlogrollFeed struct {
+	// Title is the feed's display name
+	Title string `json:"title"`
+
+	// XMLURL is the feed's URL
+	XMLURL string `json:"xmlUrl"`
+
+	// HTMLURL is the feed's human-readable site
+	HTMLURL string `json:"htmlUrl"`
+}
+
+func loadBlogroll(path string) (*BlogrollConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &BlogrollConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title string `xml:"title"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text    string `xml:"text,attr"`
+	Title   string `xml:"title,attr"`
+	XMLURL  string `xml:"xmlUrl,attr"`
+	HTMLURL string `xml:"htmlUrl,attr"`
+}
+
+// HandleBlogrollOPML serves GET /blogroll.opml, generated from
+// blogroll.json.
+func (fs *FeedService) HandleBlogrollOPML(w http.ResponseWriter, r *http.Request) {
+	fs.serveCached(w, "opml", "text/x-opml+xml; charset=utf-8", fs.renderBlogrollOPML)
+}
+
+// renderBlogrollOPML reads blogroll.json and renders it as an OPML
+// document.
+func (fs *FeedService) renderBlogrollOPML() (string, error) {
+	blogroll, err := loadBlogroll(filepath.Join(fs.BlogMarkdownDir, BlogrollFilename))
+	if err != nil {
+		return "", err
+	}
+
+	doc := opmlDocument{
+		Version: "2.0",
+		Head:    opmlHead{Title: fs.Title + " blogroll"},
+	}
+	for _, feed := range blogroll.Feeds {
+		doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{
+			Text:    feed.Title,
+			Title:   feed.Title,
+			XMLURL:  feed.XMLURL,
+			HTMLURL: feed.HTMLURL,
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
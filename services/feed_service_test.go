@@ -0,0 +1,140 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFeedService(t *testing.T, blogMarkdownDir string, posts []*BlogPost) *FeedService {
+	t.Helper()
+	return NewFeedService(fakeLogger{}, stubBlogService{posts: posts}, blogMarkdownDir, "https://example.com", "Test Blog", "a test blog", "Tester")
+}
+
+func TestFeedServiceHandleRSS(t *testing.T) {
+	posts := []*BlogPost{{Slug: "hello", Title: "Hello World", Summary: "intro", HTMLContent: "<p>hi</p>", Date: time.Now()}}
+	feedService := newTestFeedService(t, "", posts)
+
+	rec := httptest.NewRecorder()
+	feedService.HandleRSS(rec, httptest.NewRequest(http.MethodGet, "/feed.rss", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/rss+xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want application/rss+xml", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "Hello World") {
+		t.Errorf("body = %q, want it to contain the post title", rec.Body.String())
+	}
+}
+
+func TestFeedServiceHandleAtomAndJSON(t *testing.T) {
+	posts := []*BlogPost{{Slug: "hello", Title: "Hello World", Summary: "intro", HTMLContent: "<p>hi</p>", Date: time.Now()}}
+
+	t.Run("atom", func(t *testing.T) {
+		feedService := newTestFeedService(t, "", posts)
+		rec := httptest.NewRecorder()
+		feedService.HandleAtom(rec, httptest.NewRequest(http.MethodGet, "/feed.atom", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if !strings.Contains(rec.Body.String(), "Hello World") {
+			t.Errorf("body = %q, want it to contain the post title", rec.Body.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		feedService := newTestFeedService(t, "", posts)
+		rec := httptest.NewRecorder()
+		feedService.HandleJSON(rec, httptest.NewRequest(http.MethodGet, "/feed.json", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if !strings.Contains(rec.Body.String(), "Hello World") {
+			t.Errorf("body = %q, want it to contain the post title", rec.Body.String())
+		}
+	})
+}
+
+func TestFeedServiceInvalidateCacheClearsAllFormats(t *testing.T) {
+	feedService := newTestFeedService(t, "", nil)
+
+	feedService.HandleRSS(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/feed.rss", nil))
+	if _, ok := feedService.cache["rss"]; !ok {
+		t.Fatal("expected the rss body to be cached after HandleRSS")
+	}
+
+	feedService.InvalidateCache()
+	if len(feedService.cache) != 0 {
+		t.Fatalf("cache = %v, want it empty after InvalidateCache", feedService.cache)
+	}
+}
+
+// TestFeedServiceHandleBlogrollOPMLIsCached exercises the review's exact
+// concern: HandleBlogrollOPML must serve from the same cache as the
+// other formats, not re-read blogroll.json on every request.
+func TestFeedServiceHandleBlogrollOPMLIsCached(t *testing.T) {
+	dir := t.TempDir()
+	blogrollPath := filepath.Join(dir, BlogrollFilename)
+	blogrollJSON := `{"feeds":[{"title":"Foo Feed","xmlUrl":"https://foo.example/feed","htmlUrl":"https://foo.example"}]}`
+	if err := os.WriteFile(blogrollPath, []byte(blogrollJSON), 0644); err != nil {
+		t.Fatalf("unable to write blogroll.json: %v", err)
+	}
+
+	feedService := newTestFeedService(t, dir, nil)
+
+	rec := httptest.NewRecorder()
+	feedService.HandleBlogrollOPML(rec, httptest.NewRequest(http.MethodGet, "/blogroll.opml", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d, body: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "Foo Feed") {
+		t.Fatalf("body = %q, want it to contain the blogroll entry", rec.Body.String())
+	}
+	if _, ok := feedService.cache["opml"]; !ok {
+		t.Fatal("expected the opml body to be cached after HandleBlogrollOPML")
+	}
+
+	// Remove blogroll.json entirely: a second request must still
+	// succeed from the cache instead of re-reading (and failing to
+	// find) the file from disk.
+	if err := os.Remove(blogrollPath); err != nil {
+		t.Fatalf("unable to remove blogroll.json: %v", err)
+	}
+
+	rec2 := httptest.NewRecorder()
+	feedService.HandleBlogrollOPML(rec2, httptest.NewRequest(http.MethodGet, "/blogroll.opml", nil))
+	if rec2.Code != http.StatusOK {
+		t.Fatalf("cached request status = %d, want %d, body: %s", rec2.Code, http.StatusOK, rec2.Body.String())
+	}
+	if !strings.Contains(rec2.Body.String(), "Foo Feed") {
+		t.Fatalf("cached body = %q, want it to still contain the blogroll entry", rec2.Body.String())
+	}
+}
+
+func TestFeedServiceHandleBlogrollOPMLInvalidatedByReload(t *testing.T) {
+	dir := t.TempDir()
+	blogrollPath := filepath.Join(dir, BlogrollFilename)
+	if err := os.WriteFile(blogrollPath, []byte(`{"feeds":[{"title":"First"}]}`), 0644); err != nil {
+		t.Fatalf("unable to write blogroll.json: %v", err)
+	}
+
+	feedService := newTestFeedService(t, dir, nil)
+	feedService.HandleBlogrollOPML(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/blogroll.opml", nil))
+
+	if err := os.WriteFile(blogrollPath, []byte(`{"feeds":[{"title":"Second"}]}`), 0644); err != nil {
+		t.Fatalf("unable to rewrite blogroll.json: %v", err)
+	}
+	feedService.InvalidateCache()
+
+	rec := httptest.NewRecorder()
+	feedService.HandleBlogrollOPML(rec, httptest.NewRequest(http.MethodGet, "/blogroll.opml", nil))
+	if !strings.Contains(rec.Body.String(), "Second") {
+		t.Fatalf("body = %q, want the re-rendered blogroll after invalidation", rec.Body.String())
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bytes"
+	"strconv"
+
+	"gopkg.in/russross/blackfriday.v2"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+)
+
+const (
+	// RendererGoldmark selects GoldmarkRenderer, the default.
+	RendererGoldmark = "goldmark"
+
+	// RendererBlackfriday selects BlackfridayRenderer, for callers
+	// pinning the old rendering output.
+	RendererBlackfriday = "blackfriday"
+)
+
+// Heading is one entry of a BlogPost's table of contents.
+type Heading struct {
+	Level int
+	Text  string
+	ID    string
+}
+
+// MarkdownRenderer converts markdown source into rendered HTML.
+type MarkdownRenderer interface {
+	Render(src []byte) (html string, err error)
+}
+
+// HeadingRenderer is implemented by renderers that can additionally
+// extract a table of contents from markdown source, used to populate
+// BlogPost.Headings.
+type HeadingRenderer interface {
+	Headings(src []byte) ([]Heading, error)
+}
+
+func newMarkdownRenderer(logger Logger, name string) MarkdownRenderer {
+	switch name {
+	case RendererBlackfriday:
+		return &BlackfridayRenderer{}
+	case "", RendererGoldmark:
+		return NewGoldmarkRenderer()
+	default:
+		logger.Error("Unknown RendererName, falling back to goldmark", Fields{"rendererName": name})
+		return NewGoldmarkRenderer()
+	}
+}
+
+// combinedRenderer is implemented by renderers that can render HTML and
+// collect headings from a single parse, avoiding the double parse that
+// calling Render and HeadingRenderer.Headings separately would cost.
+type combinedRenderer interface {
+	renderWithHeadings(src []byte) (html string, headings []Heading, err error)
+}
+
+// renderMarkdown renders src to HTML and, where supported, its table of
+// contents. Renderers that implement combinedRenderer do both from a
+// single parse; otherwise Render and HeadingRenderer.Headings are called
+// separately.
+func renderMarkdown(renderer MarkdownRenderer, src []byte) (renderedHTML string, headings []Heading, err error) {
+	if r, ok := renderer.(combinedRenderer); ok {
+		return r.renderWithHeadings(src)
+	}
+
+	renderedHTML, err = renderer.Render(src)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if headingRenderer, ok := renderer.(HeadingRenderer); ok {
+		headings, err = headingRenderer.Headings(src)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return renderedHTML, headings, nil
+}
+
+// BlackfridayRenderer renders markdown with blackfriday.v2, the
+// service's original renderer. It does not support Headings.
+type BlackfridayRenderer struct{}
+
+func (r *BlackfridayRenderer) Render(src []byte) (string, error) {
+	return string(blackfriday.Run(src)), nil
+}
+
+// GoldmarkRenderer renders markdown with goldmark, with the GFM,
+// Footnote and Typographer extensions plus syntax highlighting for
+// fenced code blocks. It slugifies h2/h3 headings into `id=` anchors for
+// deep-linking, which Headings also exposes for rendering a table of
+// contents.
+type GoldmarkRenderer struct {
+	md goldmark.Markdown
+}
+
+func NewGoldmarkRenderer() *GoldmarkRenderer {
+	return &GoldmarkRenderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(
+				extension.GFM,
+				extension.Footnote,
+				extension.Typographer,
+				highlighting.NewHighlighting(
+					highlighting.WithStyle("github"),
+				),
+			),
+			goldmark.WithRendererOptions(
+				html.WithUnsafe(),
+			),
+			goldmark.WithParserOptions(
+				parser.WithASTTransformers(
+					util.Prioritized(&headingIDTransformer{}, 500),
+				),
+			),
+		),
+	}
+}
+
+func (r *GoldmarkRenderer) Render(src []byte) (string, error) {
+	var buf bytes.Buffer
+	if err := r.md.Convert(src, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Headings walks the parsed AST for h2/h3 nodes, reading back the `id`
+// attribute assigned by headingIDTransformer during parsing so the
+// slugs it returns always match the ones rendered into the HTML.
+func (r *GoldmarkRenderer) Headings(src []byte) ([]Heading, error) {
+	doc := r.md.Parser().Parse(text.NewReader(src))
+	return collectHeadings(doc, src), nil
+}
+
+// renderWithHeadings parses src once and reuses that single AST for both
+// the HTML render and the heading extraction, rather than parsing twice
+// as calling Render and Headings separately would.
+func (r *GoldmarkRenderer) renderWithHeadings(src []byte) (string, []Heading, error) {
+	doc := r.md.Parser().Parse(text.NewReader(src))
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, src, doc); err != nil {
+		return "", nil, err
+	}
+
+	return buf.String(), collectHeadings(doc, src), nil
+}
+
+// collectHeadings walks a parsed document for h2/h3 nodes, reading back
+// the `id` attribute headingIDTransformer assigned during parsing.
+func collectHeadings(doc ast.Node, src []byte) []Heading {
+	var headings []Heading
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		heading, ok := n.(*ast.Heading)
+		if !ok || (heading.Level != 2 && heading.Level != 3) {
+			return ast.WalkContinue, nil
+		}
+
+		id, _ := heading.AttributeString("id")
+		idBytes, _ := id.([]byte)
+
+		headings = append(headings, Heading{
+			Level: heading.Level,
+			Text:  headingText(heading, src),
+			ID:    string(idBytes),
+		})
+
+		return ast.WalkContinue, nil
+	})
+	return headings
+}
+
+// headingIDTransformer assigns a slugified `id` attribute to every h2/h3
+// heading, so templates can deep-link into a post's table of contents.
+// Duplicate slugs within a document are disambiguated with a numeric
+// suffix.
+type headingIDTransformer struct{}
+
+func (t *headingIDTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	seen := make(map[string]int)
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		heading, ok := n.(*ast.Heading)
+		if !ok || (heading.Level != 2 && heading.Level != 3) {
+			return ast.WalkContinue, nil
+		}
+
+		heading.SetAttributeString("id", []byte(headingID(headingText(heading, reader.Source()), seen)))
+		return ast.WalkContinue, nil
+	})
+}
+
+// headingText concatenates the literal text content of a heading node.
+func headingText(heading ast.Node, source []byte) string {
+	var buf bytes.Buffer
+	_ = ast.Walk(heading, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if textNode, ok := n.(*ast.Text); ok {
+			buf.Write(textNode.Value(source))
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
+
+// headingID slugifies text and disambiguates it against slugs already
+// seen in the same document.
+func headingID(text string, seen map[string]int) string {
+	slug := slugify(text)
+	if slug == "" {
+		slug = "heading"
+	}
+
+	count, exists := seen[slug]
+	seen[slug] = count + 1
+	if !exists {
+		return slug
+	}
+	return slug + "-" + strconv.Itoa(count)
+}
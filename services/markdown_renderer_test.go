@@ -0,0 +1,109 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMarkdownRendererSelectsByName(t *testing.T) {
+	cases := []struct {
+		name       string
+		wantBlack  bool
+		wantGolden bool
+	}{
+		{RendererBlackfriday, true, false},
+		{RendererGoldmark, false, true},
+		{"", false, true},
+		{"unknown-renderer", false, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			renderer := newMarkdownRenderer(fakeLogger{}, tc.name)
+
+			_, isBlack := renderer.(*BlackfridayRenderer)
+			_, isGoldmark := renderer.(*GoldmarkRenderer)
+
+			if isBlack != tc.wantBlack || isGoldmark != tc.wantGolden {
+				t.Errorf("newMarkdownRenderer(%q) = %T, want blackfriday=%v goldmark=%v", tc.name, renderer, tc.wantBlack, tc.wantGolden)
+			}
+		})
+	}
+}
+
+func TestGoldmarkRendererRender(t *testing.T) {
+	html, err := NewGoldmarkRenderer().Render([]byte("**bold**"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, "<strong>bold</strong>") {
+		t.Errorf("Render() = %q, want it to contain <strong>bold</strong>", html)
+	}
+}
+
+func TestGoldmarkRendererGFMTable(t *testing.T) {
+	src := "| A | B |\n| - | - |\n| 1 | 2 |\n"
+	html, err := NewGoldmarkRenderer().Render([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, "<table>") {
+		t.Errorf("Render() = %q, want a GFM table to produce <table>", html)
+	}
+}
+
+func TestGoldmarkRendererSyntaxHighlighting(t *testing.T) {
+	src := "```go\nfunc main() {}\n```\n"
+	html, err := NewGoldmarkRenderer().Render([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, "<pre") {
+		t.Errorf("Render() = %q, want a fenced code block to render as <pre>", html)
+	}
+}
+
+func TestGoldmarkRendererHeadingsAssignsAndDisambiguatesSlugIDs(t *testing.T) {
+	src := "## Hello\n\nbody\n\n## Hello\n"
+	renderer := NewGoldmarkRenderer()
+
+	headings, err := renderer.Headings([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(headings) != 2 {
+		t.Fatalf("len(headings) = %d, want 2", len(headings))
+	}
+	if headings[0].ID != "hello" {
+		t.Errorf("headings[0].ID = %q, want %q", headings[0].ID, "hello")
+	}
+	if headings[1].ID != "hello-1" {
+		t.Errorf("headings[1].ID = %q, want %q (disambiguated)", headings[1].ID, "hello-1")
+	}
+}
+
+func TestGoldmarkRendererHeadingIDsMatchRenderedHTML(t *testing.T) {
+	src := "## Hello World\n"
+	renderer := NewGoldmarkRenderer()
+
+	html, headings, err := renderer.renderWithHeadings([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(headings) != 1 {
+		t.Fatalf("len(headings) = %d, want 1", len(headings))
+	}
+	if !strings.Contains(html, `id="`+headings[0].ID+`"`) {
+		t.Errorf("rendered HTML = %q, want it to contain the same id as Headings() returned (%q)", html, headings[0].ID)
+	}
+}
+
+func TestBlackfridayRendererRender(t *testing.T) {
+	html, err := (&BlackfridayRenderer{}).Render([]byte("**bold**"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(html, "<strong>bold</strong>") {
+		t.Errorf("Render() = %q, want it to contain <strong>bold</strong>", html)
+	}
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SyndicationTarget is an external destination a post can be syndicated
+// to, returned from the Micropub q=syndicate-to query.
+type SyndicationTarget struct {
+	UID  string `json:"uid"`
+	Name string `json:"name"`
+}
+
+// TokenInfo is the result of successfully verifying a Micropub bearer
+// token.
+type TokenInfo struct {
+	Me       string   `json:"me"`
+	ClientID string   `json:"client_id"`
+	Scope    []string `json:"scope"`
+}
+
+// TokenVerifier authorises a Micropub request's bearer token. The
+// default implementation, IndieAuthTokenVerifier, introspects the token
+// against a configured IndieAuth endpoint.
+type TokenVerifier interface {
+	VerifyToken(token string) (*TokenInfo, error)
+}
+
+// IndieAuthTokenVerifier verifies Micropub bearer tokens via IndieAuth
+// token introspection: https://indieauth.spec.indieweb.org/#access-token-verification
+type IndieAuthTokenVerifier struct {
+	IntrospectionEndpoint string
+	HTTPClient            *http.Client
+}
+
+func NewIndieAuthTokenVerifier(introspectionEndpoint string) *IndieAuthTokenVerifier {
+	return &IndieAuthTokenVerifier{
+		IntrospectionEndpoint: introspectionEndpoint,
+		HTTPClient:            http.DefaultClient,
+	}
+}
+
+func (v *IndieAuthTokenVerifier) VerifyToken(token string) (*TokenInfo, error) {
+	req, err := http.NewRequest(http.MethodGet, v.IntrospectionEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token introspection endpoint returned status %d", resp.StatusCode)
+	}
+
+	info := &TokenInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(info); err != nil {
+		return nil, err
+	}
+	if info.Me == "" {
+		return nil, errors.New("token introspection did not return a valid token")
+	}
+
+	return info, nil
+}
+
+// MicropubService implements a W3C Micropub (https://www.w3.org/TR/micropub/)
+// endpoint in front of an InMemoryBlogService: authenticated posts become
+// new markdown files with YAML frontmatter, and the blog service is
+// reloaded immediately so the post goes live without a restart.
+type MicropubService struct {
+	Logger             Logger
+	BlogService        *InMemoryBlogService
+	BlogMarkdownDir    string
+	TokenVerifier      TokenVerifier
+	SyndicationTargets []SyndicationTarget
+
+	// BaseURL is the blog's public base URL, used to build the
+	// Location header and canonical post URLs, e.g. "https://example.com".
+	BaseURL string
+}
+
+func NewMicropubService(logger Logger, blogService *InMemoryBlogService, blogMarkdownDir string, tokenVerifier TokenVerifier, syndicationTargets []SyndicationTarget, baseURL string) *MicropubService {
+	return &MicropubService{
+		Logger:             logger,
+		BlogService:        blogService,
+		BlogMarkdownDir:    blogMarkdownDir,
+		TokenVerifier:      tokenVerifier,
+		SyndicationTargets: syndicationTargets,
+		BaseURL:            baseURL,
+	}
+}
+
+func (ms *MicropubService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ms.handleQuery(w, r)
+	case http.MethodPost:
+		ms.handleCreate(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleQuery serves the q=config and q=syndicate-to Micropub queries.
+func (ms *MicropubService) handleQuery(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("q") {
+	case "config", "syndicate-to":
+		ms.writeJSON(w, map[string]interface{}{"syndicate-to": ms.SyndicationTargets})
+	default:
+		http.Error(w, "unsupported query", http.StatusBadRequest)
+	}
+}
+
+// handleCreate authenticates and accepts a Micropub h-entry create
+// request, writing it to BlogMarkdownDir as a new markdown post.
+func (ms *MicropubService) handleCreate(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
+	if token == "" {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	info, err := ms.TokenVerifier.VerifyToken(token)
+	if err != nil {
+		ms.Logger.Error("Micropub token verification failed", Fields{"error": err.Error()})
+		http.Error(w, "invalid token", http.StatusForbidden)
+		return
+	}
+	if !hasCreateScope(info.Scope) {
+		ms.Logger.Error("Micropub token missing create scope", Fields{"me": info.Me})
+		http.Error(w, "token does not grant create scope", http.StatusForbidden)
+		return
+	}
+
+	entry, err := parseMicropubRequest(r)
+	if err != nil {
+		ms.Logger.Error("Invalid micropub request", Fields{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Always pass the slug through slugify, including when the client
+	// supplied mp-slug: slugify strips everything but [a-z0-9-], which
+	// rules out path traversal (e.g. mp-slug=../../etc/evil) reaching
+	// the filepath.Join below.
+	slug := slugify(entry.Slug)
+	if slug == "" {
+		slug = slugify(entry.Title)
+	}
+	if slug == "" {
+		http.Error(w, "unable to derive a slug, provide mp-slug or name", http.StatusBadRequest)
+		return
+	}
+
+	postPath := filepath.Join(ms.BlogMarkdownDir, slug+".md")
+	if err := writeFrontmatterMarkdown(postPath, entry, slug); err != nil {
+		ms.Logger.Error("Unable to write micropub post", Fields{"slug": slug, "error": err.Error()})
+		http.Error(w, "unable to create post", http.StatusInternalServerError)
+		return
+	}
+
+	ms.BlogService.Reload()
+
+	location := strings.TrimRight(ms.BaseURL, "/") + "/blog/" + slug
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (ms *MicropubService) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		ms.Logger.Error("Unable to encode micropub response", Fields{"error": err.Error()})
+	}
+}
+
+// micropubEntry is the subset of h-entry properties this endpoint
+// understands, collected from either a form or a JSON request body.
+type micropubEntry struct {
+	Title   string
+	Summary string
+	Content string
+	Slug    string
+	Tags    []string
+	Image   string
+}
+
+func parseMicropubRequest(r *http.Request) (*micropubEntry, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return parseMicropubJSON(r.Body)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	return parseMicropubForm(r.Form)
+}
+
+func parseMicropubForm(form url.Values) (*micropubEntry, error) {
+	entry := &micropubEntry{
+		Title:   form.Get("name"),
+		Summary: form.Get("summary"),
+		Content: form.Get("content"),
+		Slug:    form.Get("mp-slug"),
+		Image:   form.Get("photo"),
+		Tags:    form["category"],
+	}
+	if entry.Content == "" {
+		return nil, errors.New("content is required")
+	}
+	return entry, nil
+}
+
+// micropubJSONRequest is the JSON h-entry shape per the Micropub spec:
+// https://www.w3.org/TR/micropub/#json-syntax
+type micropubJSONRequest struct {
+	Type       []string                 `json:"type"`
+	Properties map[string][]interface{} `json:"properties"`
+}
+
+func parseMicropubJSON(body io.Reader) (*micropubEntry, error) {
+	req := &micropubJSONRequest{}
+	if err := json.NewDecoder(body).Decode(req); err != nil {
+		return nil, err
+	}
+
+	entry := &micropubEntry{
+		Title:   firstString(req.Properties["name"]),
+		Summary: firstString(req.Properties["summary"]),
+		Content: firstString(req.Properties["content"]),
+		Slug:    firstString(req.Properties["mp-slug"]),
+		Image:   firstString(req.Properties["photo"]),
+		Tags:    stringSlice(req.Properties["category"]),
+	}
+	if entry.Content == "" {
+		return nil, errors.New("content is required")
+	}
+	return entry, nil
+}
+
+func firstString(values []interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	s, _ := values[0].(string)
+	return s
+}
+
+func stringSlice(values []interface{}) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// writeFrontmatterMarkdown writes entry to path as a markdown file with
+// a YAML frontmatter block, in the same shape loadBlogPostsFromFrontmatter
+// expects to read back.
+func writeFrontmatterMarkdown(path string, entry *micropubEntry, slug string) error {
+	meta := blogPostFrontmatter{
+		Slug:     slug,
+		Title:    entry.Title,
+		Summary:  entry.Summary,
+		Image:    entry.Image,
+		ImageAlt: entry.Title,
+		Tags:     entry.Tags,
+		Date:     time.Now().UTC().Format(frontmatterDateLayout),
+	}
+
+	yamlBytes, err := yaml.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(frontmatterDelimiter)
+	buf.WriteString("\n")
+	buf.Write(yamlBytes)
+	buf.Write(frontmatterDelimiter)
+	buf.WriteString("\n\n")
+	buf.WriteString(entry.Content)
+	buf.WriteString("\n")
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify derives a URL-safe slug from arbitrary text (a post title, a
+// client-supplied mp-slug, or a heading): it lowercases, collapses every
+// run of characters outside [a-z0-9] into "-", and trims leading and
+// trailing "-".
+func slugify(title string) string {
+	slug := strings.ToLower(strings.TrimSpace(title))
+	slug = slugInvalidChars.ReplaceAllString(slug, "-")
+	return strings.Trim(slug, "-")
+}
+
+// hasCreateScope reports whether scope grants permission to create posts.
+// Per the IndieAuth/Micropub spec, "create" is the canonical scope but
+// "post" is accepted as a widely-used alias.
+func hasCreateScope(scope []string) bool {
+	for _, s := range scope {
+		if s == "create" || s == "post" {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken extracts the Micropub access token from the Authorization
+// header, falling back to the access_token form parameter per the
+// IndieAuth spec.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, prefix) {
+		return strings.TrimPrefix(auth, prefix)
+	}
+	return r.FormValue("access_token")
+}
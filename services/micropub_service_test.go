@@ -0,0 +1,131 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var errInvalidToken = errors.New("token introspection failed")
+
+// stubTokenVerifier is a TokenVerifier whose outcome and scope are fixed
+// by the test, so handleCreate's authorisation logic can be exercised
+// without a real IndieAuth endpoint.
+type stubTokenVerifier struct {
+	err   error
+	scope []string
+}
+
+func (v stubTokenVerifier) VerifyToken(token string) (*TokenInfo, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	return &TokenInfo{Me: "https://example.com/", Scope: v.scope}, nil
+}
+
+func newTestMicropubService(t *testing.T, verifier TokenVerifier) *MicropubService {
+	t.Helper()
+	dir := t.TempDir()
+	blogService, err := NewInMemoryBlogService(fakeLogger{}, dir, "")
+	if err != nil {
+		t.Fatalf("NewInMemoryBlogService() error: %v", err)
+	}
+	return NewMicropubService(fakeLogger{}, blogService, dir, verifier, nil, "https://example.com")
+}
+
+func newCreateRequest(content string) *http.Request {
+	form := url.Values{"content": {content}, "name": {"A Post"}}
+	req := httptest.NewRequest(http.MethodPost, "/micropub", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer test-token")
+	return req
+}
+
+func TestHandleCreateRejectsTokenWithoutCreateScope(t *testing.T) {
+	ms := newTestMicropubService(t, stubTokenVerifier{scope: []string{"read"}})
+
+	rec := httptest.NewRecorder()
+	ms.ServeHTTP(rec, newCreateRequest("hello world"))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if entries, err := os.ReadDir(ms.BlogMarkdownDir); err != nil || len(entries) != 0 {
+		t.Fatalf("expected no markdown file to be written, got entries=%v err=%v", entries, err)
+	}
+}
+
+func TestHandleCreateAcceptsCreateOrPostScope(t *testing.T) {
+	for _, scope := range []string{"create", "post"} {
+		t.Run(scope, func(t *testing.T) {
+			ms := newTestMicropubService(t, stubTokenVerifier{scope: []string{"profile", scope}})
+
+			rec := httptest.NewRecorder()
+			ms.ServeHTTP(rec, newCreateRequest("hello world"))
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+			}
+			if loc := rec.Header().Get("Location"); loc == "" {
+				t.Fatal("expected a Location header on the created post")
+			}
+			if _, err := os.Stat(filepath.Join(ms.BlogMarkdownDir, "a-post.md")); err != nil {
+				t.Fatalf("expected post markdown file to be written: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleCreateRejectsInvalidToken(t *testing.T) {
+	ms := newTestMicropubService(t, stubTokenVerifier{err: errInvalidToken})
+
+	rec := httptest.NewRecorder()
+	ms.ServeHTTP(rec, newCreateRequest("hello world"))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestHandleQueryReturnsSyndicationTargets(t *testing.T) {
+	ms := newTestMicropubService(t, stubTokenVerifier{scope: []string{"create"}})
+	ms.SyndicationTargets = []SyndicationTarget{{UID: "https://example.com/twitter", Name: "Twitter"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/micropub?q=config", nil)
+	rec := httptest.NewRecorder()
+	ms.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if !strings.Contains(rec.Body.String(), "Twitter") {
+		t.Fatalf("response body %q missing syndication target", rec.Body.String())
+	}
+}
+
+func TestSlugifyRejectsPathTraversal(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+	}{
+		{"dot-dot-slash", "../../../../etc/evil"},
+		{"absolute-path", "/etc/evil"},
+		{"backslash", `..\..\evil`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := slugify(tc.in)
+			for _, c := range got {
+				if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+					t.Fatalf("slugify(%q) = %q, contains disallowed character %q", tc.in, got, c)
+				}
+			}
+		})
+	}
+}
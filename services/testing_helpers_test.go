@@ -0,0 +1,28 @@
+package services
+
+// fakeLogger is a no-op Logger for tests that need to satisfy a
+// constructor's signature but don't exercise any logged error path.
+type fakeLogger struct{}
+
+func (fakeLogger) Debug(msg string, fields Fields) {}
+func (fakeLogger) Info(msg string, fields Fields)  {}
+func (fakeLogger) Error(msg string, fields Fields) {}
+
+// stubBlogService is a minimal BlogService backed by a fixed slice of
+// posts, for tests that need one without a real markdown directory.
+type stubBlogService struct {
+	posts []*BlogPost
+}
+
+func (s stubBlogService) Posts() []*BlogPost {
+	return s.posts
+}
+
+func (s stubBlogService) PostBySlug(slug string) *BlogPost {
+	for _, post := range s.posts {
+		if post.Slug == slug {
+			return post
+		}
+	}
+	return nil
+}